@@ -21,9 +21,7 @@ package gconfig
 import (
 	"errors"
 	"fmt"
-	"io/ioutil"
 	"log"
-	"reflect"
 	"strings"
 
 	"github.com/purpleidea/mgmt/etcd"
@@ -33,7 +31,7 @@ import (
 	"github.com/purpleidea/mgmt/resources"
 	"github.com/purpleidea/mgmt/util"
 
-	"gopkg.in/yaml.v2"
+	"gopkg.in/yaml.v3"
 )
 
 type collectorResConfig struct {
@@ -54,16 +52,10 @@ type edgeConfig struct {
 
 // GraphConfig is the data structure that describes a single graph to run.
 type GraphConfig struct {
-	Graph     string `yaml:"graph"`
-	Resources struct {
-		Noop  []*resources.NoopRes  `yaml:"noop"`
-		Pkg   []*resources.PkgRes   `yaml:"pkg"`
-		File  []*resources.FileRes  `yaml:"file"`
-		Svc   []*resources.SvcRes   `yaml:"svc"`
-		Exec  []*resources.ExecRes  `yaml:"exec"`
-		Timer []*resources.TimerRes `yaml:"timer"`
-		Msg   []*resources.MsgRes   `yaml:"msg"`
-	} `yaml:"resources"`
+	Graph     string               `yaml:"graph"`
+	Include   []string             `yaml:"include"`  // local globs and/or http(s):// or etcd:// fragment URIs
+	Selector  []string             `yaml:"selector"` // only merge this fragment into a ParseConfigTree when the local hostname is in this list; empty matches everywhere
+	Resources resourcesConfig      `yaml:"resources"`
 	Collector []collectorResConfig `yaml:"collect"`
 	Edges     []edgeConfig         `yaml:"edges"`
 	Comment   string               `yaml:"comment"`
@@ -82,21 +74,31 @@ func (c *GraphConfig) Parse(data []byte) error {
 	return nil
 }
 
-// ParseConfigFromFile takes a filename and returns the graph config structure.
+// ParseConfigFromFile takes a filename and returns the graph config
+// structure. The file format (yaml, jsonnet, hcl) is picked by looking at
+// the filename extension; see ConfigLoader for the pluggable front-end.
 func ParseConfigFromFile(filename string) *GraphConfig {
-	data, err := ioutil.ReadFile(filename)
+	return ParseConfigFromFileWithExtVars(filename, nil, nil)
+}
+
+// ParseConfigFromFileWithExtVars is ParseConfigFromFile, plus extStr/extCode,
+// the jsonnet VM's `--ext-str key=val` / `--ext-code key=val` external
+// variables. They're only consulted when filename is a ".jsonnet" file;
+// every other format ignores them.
+func ParseConfigFromFileWithExtVars(filename string, extStr, extCode map[string]string) *GraphConfig {
+	loader, err := loaderForFileWithExtVars(filename, extStr, extCode)
 	if err != nil {
-		log.Printf("Config: Error: ParseConfigFromFile: File: %v", err)
+		log.Printf("Config: Error: ParseConfigFromFile: %v", err)
 		return nil
 	}
 
-	var config GraphConfig
-	if err := config.Parse(data); err != nil {
-		log.Printf("Config: Error: ParseConfigFromFile: Parse: %v", err)
+	config, err := loader.Load(filename)
+	if err != nil {
+		log.Printf("Config: Error: ParseConfigFromFile: %v", err)
 		return nil
 	}
 
-	return &config
+	return config
 }
 
 // NewGraphFromConfig returns a new graph from existing input, such as from the
@@ -113,6 +115,12 @@ func (c *GraphConfig) NewGraphFromConfig(g *pgraph.Graph, embdEtcd *etcd.EmbdEtc
 		graph = g.Copy() // same vertices, since they're pointers!
 	}
 
+	if embdEtcd != nil {
+		// make `secret://etcd/...` refs resolvable against the same
+		// cluster we already use for exported resources.
+		RegisterSecretResolver("etcd", NewEtcdSecretResolver(embdEtcd))
+	}
+
 	var lookup = make(map[string]map[string]*pgraph.Vertex)
 
 	//log.Printf("%+v", config) // debug
@@ -120,54 +128,52 @@ func (c *GraphConfig) NewGraphFromConfig(g *pgraph.Graph, embdEtcd *etcd.EmbdEtc
 	// TODO: if defined (somehow)...
 	graph.SetName(c.Graph) // set graph name
 
-	var keep []*pgraph.Vertex        // list of vertex which are the same in new graph
-	var resourceList []resources.Res // list of resources to export
-	// use reflection to avoid duplicating code... better options welcome!
-	value := reflect.Indirect(reflect.ValueOf(c.Resources))
-	vtype := value.Type()
-	for i := 0; i < vtype.NumField(); i++ { // number of fields in struct
-		name := vtype.Field(i).Name // string of field name
-		field := value.FieldByName(name)
-		iface := field.Interface() // interface type of value
-		slice := reflect.ValueOf(iface)
-		// XXX: should we just drop these everywhere and have the kind strings be all lowercase?
-		kind := util.FirstToUpper(name)
+	var keep []*pgraph.Vertex              // list of vertex which are the same in new graph
+	var resourceList []resources.Res       // list of resources to export
+	secretCache := make(map[string][]byte) // resolved secret:// refs, valid for this graph-load only
+	// each entry knows its own `kind` and builds itself via the
+	// resources.Registry, so we no longer need to reflect over a
+	// hard-coded struct field per kind.
+	for _, rc := range c.Resources {
+		kind := util.FirstToUpper(rc.Kind)
+		res, err := rc.Build()
+		if err != nil {
+			return nil, err
+		}
+		if err := resolveResourceSecrets(res, secretCache); err != nil {
+			return nil, err
+		}
 		if global.DEBUG {
-			log.Printf("Config: Processing: %v...", kind)
+			// resolved secret values live in res now; redact them
+			// before they can ever hit a log line.
+			log.Printf("Config: Resolved: %s", redactSecrets(fmt.Sprintf("%+v", res), secretCache))
 		}
-		for j := 0; j < slice.Len(); j++ { // loop through resources of same kind
-			x := slice.Index(j).Interface()
-			res, ok := x.(resources.Res) // convert to Res type
-			if !ok {
-				return nil, fmt.Errorf("Config: Error: Can't convert: %v of type: %T to Res.", x, x)
-			}
-			if noop {
-				res.Meta().Noop = noop
-			}
-			if _, exists := lookup[kind]; !exists {
-				lookup[kind] = make(map[string]*pgraph.Vertex)
-			}
-			// XXX: should we export based on a @@ prefix, or a metaparam
-			// like exported => true || exported => (host pattern)||(other pattern?)
-			if !strings.HasPrefix(res.GetName(), "@@") { // not exported resource
-				// XXX: we don't have a way of knowing if any of the
-				// metaparams are undefined, and as a result to set the
-				// defaults that we want! I hate the go yaml parser!!!
-				v := graph.GetVertexMatch(res)
-				if v == nil { // no match found
-					res.Init()
-					v = pgraph.NewVertex(res)
-					graph.AddVertex(v) // call standalone in case not part of an edge
-				}
-				lookup[kind][res.GetName()] = v // used for constructing edges
-				keep = append(keep, v)          // append
-
-			} else if !noop { // do not export any resources if noop
-				// store for addition to etcd storage...
-				res.SetName(res.GetName()[2:]) //slice off @@
-				res.SetKind(kind)              // cheap init
-				resourceList = append(resourceList, res)
+		if noop {
+			res.Meta().Noop = noop
+		}
+		if _, exists := lookup[kind]; !exists {
+			lookup[kind] = make(map[string]*pgraph.Vertex)
+		}
+		// XXX: should we export based on a @@ prefix, or a metaparam
+		// like exported => true || exported => (host pattern)||(other pattern?)
+		if !strings.HasPrefix(res.GetName(), "@@") { // not exported resource
+			// XXX: we don't have a way of knowing if any of the
+			// metaparams are undefined, and as a result to set the
+			// defaults that we want! I hate the go yaml parser!!!
+			v := graph.GetVertexMatch(res)
+			if v == nil { // no match found
+				res.Init()
+				v = pgraph.NewVertex(res)
+				graph.AddVertex(v) // call standalone in case not part of an edge
 			}
+			lookup[kind][res.GetName()] = v // used for constructing edges
+			keep = append(keep, v)          // append
+
+		} else if !noop { // do not export any resources if noop
+			// store for addition to etcd storage...
+			res.SetName(res.GetName()[2:]) //slice off @@
+			res.SetKind(kind)              // cheap init
+			resourceList = append(resourceList, res)
 		}
 	}
 	// store in etcd
@@ -0,0 +1,59 @@
+// Mgmt
+// Copyright (C) 2013-2016+ James Shubin and the project contributors
+// Written by James Shubin <james@shubin.ca> and the project contributors
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package gconfig
+
+import (
+	"fmt"
+	"io/ioutil"
+
+	"github.com/hashicorp/hcl"
+	"gopkg.in/yaml.v3"
+)
+
+// hclLoader implements ConfigLoader for HCL graph configs.
+type hclLoader struct{}
+
+// Load reads filename and decodes it as HCL into a GraphConfig. HCL is
+// decoded into a generic tree first and re-marshalled to yaml, rather than
+// decoded straight into GraphConfig, so that it goes through the same
+// config.Parse path (and the yaml.Node-based resourceConfig.UnmarshalYAML)
+// as every other loader instead of bypassing the resources.Registry
+// dispatch.
+func (obj *hclLoader) Load(filename string) (*GraphConfig, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("Config: Error: hclLoader: File: %v", err)
+	}
+
+	var raw map[string]interface{}
+	if err := hcl.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("Config: Error: hclLoader: Unmarshal: %v", err)
+	}
+
+	intermediate, err := yaml.Marshal(raw)
+	if err != nil {
+		return nil, fmt.Errorf("Config: Error: hclLoader: Marshal: %v", err)
+	}
+
+	var config GraphConfig
+	if err := config.Parse(intermediate); err != nil {
+		return nil, fmt.Errorf("Config: Error: hclLoader: Parse: %v", err)
+	}
+
+	return &config, nil
+}
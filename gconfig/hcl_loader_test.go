@@ -0,0 +1,72 @@
+// Mgmt
+// Copyright (C) 2013-2016+ James Shubin and the project contributors
+// Written by James Shubin <james@shubin.ca> and the project contributors
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package gconfig
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+// TestHclLoaderLoad round-trips a small HCL file with a flat `resources:`
+// entry through hclLoader.Load, so a future change to the generic-tree /
+// re-marshal-to-yaml trick can't silently regress it unnoticed.
+func TestHclLoaderLoad(t *testing.T) {
+	const data = `
+graph = "g1"
+
+resources = [
+  {
+    kind = "noop"
+    name = "a"
+  }
+]
+`
+	f, err := ioutil.TempFile("", "hcl-loader-test-*.hcl")
+	if err != nil {
+		t.Fatalf("TempFile: unexpected error: %v", err)
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.WriteString(data); err != nil {
+		t.Fatalf("WriteString: unexpected error: %v", err)
+	}
+	f.Close()
+
+	loader := &hclLoader{}
+	config, err := loader.Load(f.Name())
+	if err != nil {
+		t.Fatalf("Load: unexpected error: %v", err)
+	}
+	if config.Graph != "g1" {
+		t.Errorf("Load: got Graph %q, want %q", config.Graph, "g1")
+	}
+	if len(config.Resources) != 1 {
+		t.Fatalf("Load: got %v resources, want 1", len(config.Resources))
+	}
+	if config.Resources[0].Kind != "noop" || config.Resources[0].Name != "a" {
+		t.Errorf("Load: got resource %+v, want kind=noop name=a", config.Resources[0])
+	}
+
+	res, err := config.Resources[0].Build()
+	if err != nil {
+		t.Fatalf("Build: unexpected error: %v", err)
+	}
+	if res.GetName() != "a" {
+		t.Errorf("Build: got name %q, want %q", res.GetName(), "a")
+	}
+}
@@ -0,0 +1,249 @@
+// Mgmt
+// Copyright (C) 2013-2016+ James Shubin and the project contributors
+// Written by James Shubin <james@shubin.ca> and the project contributors
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package gconfig
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/purpleidea/mgmt/etcd"
+
+	"gopkg.in/yaml.v3"
+)
+
+// includeCacheDir is where fetched remote includes are cached on disk,
+// keyed by the sha256 of their content.
+var includeCacheDir = filepath.Join(os.TempDir(), "mgmt-include-cache")
+
+// ParseConfigTree loads root and recursively merges any fragments it
+// references via `include:`, returning the single, flattened GraphConfig
+// that would be turned into a pgraph.Graph. Fragments can be local globs,
+// or http(s):// / etcd:// URIs, and each included fragment is only merged
+// if its own Selector (if set) lists the local hostname, so a tree can mix
+// shared, host-agnostic "library" fragments with host-specific overlays.
+// The root document itself is never selector-checked, matching
+// ParseConfigFromFile: `mgmt run --file root.yaml` always loads root.yaml.
+func ParseConfigTree(root string) (*GraphConfig, error) {
+	hostname, err := os.Hostname()
+	if err != nil {
+		return nil, fmt.Errorf("Config: Error: ParseConfigTree: Hostname: %v", err)
+	}
+
+	loader, err := loaderForFile(root)
+	if err != nil {
+		return nil, err
+	}
+	config, err := loader.Load(root)
+	if err != nil {
+		return nil, fmt.Errorf("Config: Error: ParseConfigTree: %v: %v", root, err)
+	}
+
+	seen := make(map[string]struct{}) // (kind, name) pairs already merged
+	if err := mergeSeen(config.Resources, seen); err != nil {
+		return nil, fmt.Errorf("Config: Error: ParseConfigTree: %v: %v", root, err)
+	}
+
+	if err := mergeIncludes(config, hostname, seen); err != nil {
+		return nil, err
+	}
+
+	return config, nil
+}
+
+// mergeIncludes walks config.Include, recursively loading and merging each
+// fragment's Resources, Edges and Collector into config, and propagating
+// the first non-empty child Hostname onto config if it doesn't already
+// have one. This is how a host-agnostic root/library fragment picks up the
+// Hostname set by a host-specific overlay it includes.
+func mergeIncludes(config *GraphConfig, hostname string, seen map[string]struct{}) error {
+	for _, include := range config.Include {
+		fragments, err := resolveInclude(include)
+		if err != nil {
+			return fmt.Errorf("Config: Error: ParseConfigTree: include: %v: %v", include, err)
+		}
+		for _, fragment := range fragments {
+			child, err := parseConfigFragment(fragment, hostname, seen)
+			if err != nil {
+				return err
+			}
+			if child == nil {
+				continue // selector didn't match; this fragment contributes nothing
+			}
+
+			config.Resources = append(config.Resources, child.Resources...)
+			config.Edges = append(config.Edges, child.Edges...)
+			config.Collector = append(config.Collector, child.Collector...)
+			if config.Hostname == "" {
+				config.Hostname = child.Hostname
+			}
+		}
+	}
+	return nil
+}
+
+// parseConfigFragment loads filename, checks its Selector against
+// hostname (nil if it doesn't match, so it's dropped), merges its own
+// resources into seen, and recurses into any `include:` entries it has.
+func parseConfigFragment(filename, hostname string, seen map[string]struct{}) (*GraphConfig, error) {
+	loader, err := loaderForFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	config, err := loader.Load(filename)
+	if err != nil {
+		return nil, fmt.Errorf("Config: Error: ParseConfigTree: %v: %v", filename, err)
+	}
+
+	if len(config.Selector) > 0 && !selectorMatches(config.Selector, hostname) {
+		return nil, nil
+	}
+
+	if err := mergeSeen(config.Resources, seen); err != nil {
+		return nil, fmt.Errorf("Config: Error: ParseConfigTree: %v: %v", filename, err)
+	}
+
+	if err := mergeIncludes(config, hostname, seen); err != nil {
+		return nil, err
+	}
+
+	return config, nil
+}
+
+// selectorMatches returns whether hostname appears in selector.
+func selectorMatches(selector []string, hostname string) bool {
+	for _, h := range selector {
+		if h == hostname {
+			return true
+		}
+	}
+	return false
+}
+
+// mergeSeen records each (kind, name) pair from resources into seen,
+// returning a clear error the first time it finds a duplicate anywhere in
+// the tree.
+func mergeSeen(resources resourcesConfig, seen map[string]struct{}) error {
+	for _, rc := range resources {
+		key := rc.Kind + "/" + rc.Name
+		if _, exists := seen[key]; exists {
+			return fmt.Errorf("duplicate resource: %v", key)
+		}
+		seen[key] = struct{}{}
+	}
+	return nil
+}
+
+// resolveInclude expands a single `include:` entry into the list of
+// filenames to load: local entries are globbed, remote entries are fetched
+// (and cached on disk, keyed by content hash) and returned as the path to
+// the cached copy.
+func resolveInclude(include string) ([]string, error) {
+	switch {
+	case strings.HasPrefix(include, "http://"), strings.HasPrefix(include, "https://"):
+		path, err := fetchRemoteInclude(include)
+		if err != nil {
+			return nil, err
+		}
+		return []string{path}, nil
+
+	case strings.HasPrefix(include, "etcd://"):
+		path, err := fetchEtcdInclude(include)
+		if err != nil {
+			return nil, err
+		}
+		return []string{path}, nil
+
+	default:
+		matches, err := filepath.Glob(include)
+		if err != nil {
+			return nil, err
+		}
+		if len(matches) == 0 {
+			// a typo'd include: path would otherwise silently
+			// produce an incomplete graph with no error at all,
+			// unlike a bad http(s):// or etcd:// fetch above.
+			return nil, fmt.Errorf("Config: Error: resolveInclude: %v: no files matched", include)
+		}
+		return matches, nil
+	}
+}
+
+// fetchRemoteInclude downloads url and caches it on disk.
+func fetchRemoteInclude(url string) (string, error) {
+	resp, err := http.Get(url)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+	return cacheInclude(data, filepath.Ext(url))
+}
+
+// fetchEtcdInclude reads the fragment stored at the `etcd://<key>` uri and
+// caches it on disk.
+//
+// XXX: this reuses the etcd cluster mgmt already has for exported
+// resources, but needs an *etcd.EmbdEtcd threaded through from the caller
+// instead of dialing its own connection; wire that up once ParseConfigTree
+// grows an EmbdEtcd parameter.
+func fetchEtcdInclude(uri string) (string, error) {
+	key := strings.TrimPrefix(uri, "etcd://")
+	data, err := etcd.EtcdGetInclude(key)
+	if err != nil {
+		return "", err
+	}
+	return cacheInclude(data, ".yaml")
+}
+
+// cacheInclude writes data to includeCacheDir, named by its content hash,
+// and returns the path to the cached copy. If it's already cached, the
+// existing copy is reused as-is.
+func cacheInclude(data []byte, ext string) (string, error) {
+	sum := sha256.Sum256(data)
+	name := hex.EncodeToString(sum[:]) + ext
+
+	if err := os.MkdirAll(includeCacheDir, 0755); err != nil {
+		return "", err
+	}
+	path := filepath.Join(includeCacheDir, name)
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		if err := ioutil.WriteFile(path, data, 0644); err != nil {
+			return "", err
+		}
+	}
+	return path, nil
+}
+
+// RenderEffectiveConfig marshals c back into yaml, so that a
+// `--dump-effective-config` flag can show exactly what ParseConfigTree
+// merged before it's turned into a pgraph.Graph. Resources that came in via
+// the legacy nested `resources:` schema are written as kind and name only
+// (see resourceConfig.MarshalYAML).
+func RenderEffectiveConfig(c *GraphConfig) ([]byte, error) {
+	return yaml.Marshal(c)
+}
@@ -0,0 +1,172 @@
+// Mgmt
+// Copyright (C) 2013-2016+ James Shubin and the project contributors
+// Written by James Shubin <james@shubin.ca> and the project contributors
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package gconfig
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// writeIncludeFixture writes data to name inside dir and returns the full
+// path, for building small on-disk config trees in tests.
+func writeIncludeFixture(t *testing.T, dir, name, data string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := ioutil.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatalf("WriteFile: unexpected error: %v", err)
+	}
+	return path
+}
+
+// TestParseConfigTreePropagatesChildHostname checks the motivating
+// use case from the request: a host-agnostic root/library fragment with
+// no Hostname of its own, including a host-specific overlay that sets
+// Hostname, ends up with that Hostname on the merged, top-level config.
+func TestParseConfigTreePropagatesChildHostname(t *testing.T) {
+	dir, err := ioutil.TempDir("", "include-test-")
+	if err != nil {
+		t.Fatalf("TempDir: unexpected error: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	overlay := writeIncludeFixture(t, dir, "overlay.yaml", `
+graph: overlay
+hostname: host1
+resources: []
+`)
+	root := writeIncludeFixture(t, dir, "root.yaml", `
+graph: g1
+include:
+- `+overlay+`
+resources: []
+`)
+
+	config, err := ParseConfigTree(root)
+	if err != nil {
+		t.Fatalf("ParseConfigTree: unexpected error: %v", err)
+	}
+	if config.Hostname != "host1" {
+		t.Errorf("ParseConfigTree: got Hostname %q, want %q", config.Hostname, "host1")
+	}
+}
+
+// TestParseConfigFragmentSelector checks that a fragment with a Selector
+// is only merged when the given hostname is in it, and that a fragment
+// with no Selector always matches.
+func TestParseConfigFragmentSelector(t *testing.T) {
+	dir, err := ioutil.TempDir("", "include-test-")
+	if err != nil {
+		t.Fatalf("TempDir: unexpected error: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	matching := writeIncludeFixture(t, dir, "matching.yaml", `
+graph: g1
+selector: [host1]
+resources:
+- kind: noop
+  name: a
+`)
+	seen := make(map[string]struct{})
+	config, err := parseConfigFragment(matching, "host1", seen)
+	if err != nil {
+		t.Fatalf("parseConfigFragment: unexpected error: %v", err)
+	}
+	if config == nil {
+		t.Fatal("parseConfigFragment: got nil config for a matching selector")
+	}
+
+	seen = make(map[string]struct{})
+	config, err = parseConfigFragment(matching, "host2", seen)
+	if err != nil {
+		t.Fatalf("parseConfigFragment: unexpected error: %v", err)
+	}
+	if config != nil {
+		t.Error("parseConfigFragment: expected nil config for a non-matching selector")
+	}
+}
+
+// TestParseConfigTreeRootIgnoresSelector checks that the root document
+// passed to ParseConfigTree is always loaded, even if its own Selector
+// (or, previously, Hostname) wouldn't match the local host — mirroring
+// ParseConfigFromFile, which always loads the file it's given.
+func TestParseConfigTreeRootIgnoresSelector(t *testing.T) {
+	dir, err := ioutil.TempDir("", "include-test-")
+	if err != nil {
+		t.Fatalf("TempDir: unexpected error: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	root := writeIncludeFixture(t, dir, "root.yaml", `
+graph: g1
+hostname: some-other-config-hostname
+selector: [definitely-not-the-local-hostname]
+resources:
+- kind: noop
+  name: a
+`)
+
+	config, err := ParseConfigTree(root)
+	if err != nil {
+		t.Fatalf("ParseConfigTree: unexpected error: %v", err)
+	}
+	if len(config.Resources) != 1 {
+		t.Fatalf("ParseConfigTree: got %v resources, want 1 (root should never be selector-checked)", len(config.Resources))
+	}
+}
+
+// TestResolveIncludeNoMatchIsAnError checks that a local include glob that
+// matches zero files (eg: a typo'd path) is a clean error instead of
+// being silently dropped, which would otherwise produce an incomplete
+// graph with no error or log at all.
+func TestResolveIncludeNoMatchIsAnError(t *testing.T) {
+	dir, err := ioutil.TempDir("", "include-test-")
+	if err != nil {
+		t.Fatalf("TempDir: unexpected error: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	if _, err := resolveInclude(filepath.Join(dir, "does-not-exist-*.yaml")); err == nil {
+		t.Error("resolveInclude: expected an error for a glob matching zero files")
+	}
+}
+
+// TestParseConfigTreeTypoedIncludeErrors checks the same thing end-to-end
+// through ParseConfigTree: a root document with a mistyped include: entry
+// should fail loudly, not silently return an incomplete graph.
+func TestParseConfigTreeTypoedIncludeErrors(t *testing.T) {
+	dir, err := ioutil.TempDir("", "include-test-")
+	if err != nil {
+		t.Fatalf("TempDir: unexpected error: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	root := writeIncludeFixture(t, dir, "root.yaml", `
+graph: g1
+hostname: host1
+include:
+- `+filepath.Join(dir, "typoed-fragment.yaml")+`
+resources: []
+`)
+
+	if _, err := ParseConfigTree(root); err == nil {
+		t.Error("ParseConfigTree: expected an error for a typo'd include: path")
+	}
+}
@@ -0,0 +1,71 @@
+// Mgmt
+// Copyright (C) 2013-2016+ James Shubin and the project contributors
+// Written by James Shubin <james@shubin.ca> and the project contributors
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package gconfig
+
+import (
+	"fmt"
+	"io/ioutil"
+
+	"github.com/google/go-jsonnet"
+)
+
+// jsonnetLoader implements ConfigLoader for jsonnet graph configs. Jsonnet
+// lets users generate large graphs (eg: hundreds of FileRes entries that
+// share metaparams) from a real programming language, instead of
+// hand-writing the equivalent YAML.
+type jsonnetLoader struct {
+	// ExtStr are the `--ext-str key=val` string external variables
+	// passed into the jsonnet VM before evaluation.
+	ExtStr map[string]string
+	// ExtCode are the `--ext-code key=val` jsonnet external variables
+	// passed into the jsonnet VM before evaluation.
+	ExtCode map[string]string
+}
+
+// Load reads filename, evaluates it as jsonnet, and parses the resulting
+// JSON into a GraphConfig. The evaluated output is parsed the same way as
+// a yaml file (JSON is a valid subset of YAML) rather than via
+// encoding/json, because GraphConfig.Resources needs the yaml.Node-based
+// decode in resourceConfig.UnmarshalYAML to dispatch each entry through
+// the resources.Registry; encoding/json would silently leave it nil.
+func (obj *jsonnetLoader) Load(filename string) (*GraphConfig, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("Config: Error: jsonnetLoader: File: %v", err)
+	}
+
+	vm := jsonnet.MakeVM()
+	for k, v := range obj.ExtStr {
+		vm.ExtVar(k, v)
+	}
+	for k, v := range obj.ExtCode {
+		vm.ExtCode(k, v)
+	}
+
+	out, err := vm.EvaluateSnippet(filename, string(data))
+	if err != nil {
+		return nil, fmt.Errorf("Config: Error: jsonnetLoader: Evaluate: %v", err)
+	}
+
+	var config GraphConfig
+	if err := config.Parse([]byte(out)); err != nil {
+		return nil, fmt.Errorf("Config: Error: jsonnetLoader: Parse: %v", err)
+	}
+
+	return &config, nil
+}
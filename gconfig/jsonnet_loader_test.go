@@ -0,0 +1,70 @@
+// Mgmt
+// Copyright (C) 2013-2016+ James Shubin and the project contributors
+// Written by James Shubin <james@shubin.ca> and the project contributors
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package gconfig
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+// TestJsonnetLoaderExtVars actually evaluates a jsonnet snippet through
+// go-jsonnet's VM, using an ext-str and an ext-code value to generate a
+// handful of resources with a loop, and checks both show up correctly in
+// the resulting GraphConfig. This is the headline use case of the
+// jsonnet loader; loaderForFileWithExtVars only checks the values reach
+// the loader struct, not that the VM actually does anything with them.
+func TestJsonnetLoaderExtVars(t *testing.T) {
+	const snippet = `
+local env = std.extVar("env");
+local replicas = std.extVar("replicas");
+{
+  graph: "g1",
+  resources: [
+    { kind: "noop", name: env + "-" + std.toString(i) }
+    for i in std.range(0, replicas - 1)
+  ],
+}
+`
+	f, err := ioutil.TempFile("", "jsonnet-loader-test-*.jsonnet")
+	if err != nil {
+		t.Fatalf("TempFile: unexpected error: %v", err)
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.WriteString(snippet); err != nil {
+		t.Fatalf("WriteString: unexpected error: %v", err)
+	}
+	f.Close()
+
+	loader := &jsonnetLoader{
+		ExtStr:  map[string]string{"env": "prod"},
+		ExtCode: map[string]string{"replicas": "3"},
+	}
+	config, err := loader.Load(f.Name())
+	if err != nil {
+		t.Fatalf("Load: unexpected error: %v", err)
+	}
+	if len(config.Resources) != 3 {
+		t.Fatalf("Load: got %v resources, want 3", len(config.Resources))
+	}
+	for i, want := range []string{"prod-0", "prod-1", "prod-2"} {
+		if config.Resources[i].Name != want {
+			t.Errorf("Load: resource %v: got name %q, want %q", i, config.Resources[i].Name, want)
+		}
+	}
+}
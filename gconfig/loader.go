@@ -0,0 +1,65 @@
+// Mgmt
+// Copyright (C) 2013-2016+ James Shubin and the project contributors
+// Written by James Shubin <james@shubin.ca> and the project contributors
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package gconfig
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// ConfigLoader is implemented by anything that can turn a config file on
+// disk into a GraphConfig. This lets ParseConfigFromFile support more than
+// one input format (yaml, jsonnet, hcl, ...) behind a single front-end.
+type ConfigLoader interface {
+	// Load reads filename and returns the parsed GraphConfig.
+	Load(filename string) (*GraphConfig, error)
+}
+
+// loaders maps a file extension (including the leading dot) to the
+// ConfigLoader responsible for it. ".jsonnet" isn't in here: it needs a
+// fresh jsonnetLoader per call so --ext-str/--ext-code can be threaded
+// through, so loaderForFile special-cases it instead.
+var loaders = map[string]ConfigLoader{
+	".yaml": &yamlLoader{},
+	".yml":  &yamlLoader{},
+	".hcl":  &hclLoader{},
+}
+
+// loaderForFile picks the ConfigLoader to use based on the filename
+// extension, with no jsonnet external variables set. This is what every
+// caller other than ParseConfigFromFileWithExtVars wants.
+func loaderForFile(filename string) (ConfigLoader, error) {
+	return loaderForFileWithExtVars(filename, nil, nil)
+}
+
+// loaderForFileWithExtVars is loaderForFile, but for a ".jsonnet" file it
+// builds a jsonnetLoader carrying extStr/extCode so they reach the jsonnet
+// VM as `--ext-str`/`--ext-code` external variables. Every other extension
+// ignores extStr/extCode.
+func loaderForFileWithExtVars(filename string, extStr, extCode map[string]string) (ConfigLoader, error) {
+	ext := strings.ToLower(filepath.Ext(filename))
+	if ext == ".jsonnet" {
+		return &jsonnetLoader{ExtStr: extStr, ExtCode: extCode}, nil
+	}
+	loader, exists := loaders[ext]
+	if !exists {
+		return nil, fmt.Errorf("Config: Error: no ConfigLoader registered for extension: %v", ext)
+	}
+	return loader, nil
+}
@@ -0,0 +1,56 @@
+// Mgmt
+// Copyright (C) 2013-2016+ James Shubin and the project contributors
+// Written by James Shubin <james@shubin.ca> and the project contributors
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package gconfig
+
+import "testing"
+
+// TestLoaderForFileWithExtVarsJsonnet checks that a ".jsonnet" file gets a
+// jsonnetLoader carrying the given extStr/extCode, instead of the
+// permanently-zero-value instance that used to live in the loaders map.
+func TestLoaderForFileWithExtVarsJsonnet(t *testing.T) {
+	extStr := map[string]string{"env": "prod"}
+	extCode := map[string]string{"replicas": "3"}
+
+	loader, err := loaderForFileWithExtVars("graph.jsonnet", extStr, extCode)
+	if err != nil {
+		t.Fatalf("loaderForFileWithExtVars: unexpected error: %v", err)
+	}
+
+	jl, ok := loader.(*jsonnetLoader)
+	if !ok {
+		t.Fatalf("loaderForFileWithExtVars: got %T, want *jsonnetLoader", loader)
+	}
+	if jl.ExtStr["env"] != "prod" {
+		t.Errorf("loaderForFileWithExtVars: got ExtStr %+v, want env=prod", jl.ExtStr)
+	}
+	if jl.ExtCode["replicas"] != "3" {
+		t.Errorf("loaderForFileWithExtVars: got ExtCode %+v, want replicas=3", jl.ExtCode)
+	}
+}
+
+// TestLoaderForFileIgnoresExtVarsForYaml checks that extStr/extCode have no
+// effect on a non-jsonnet extension.
+func TestLoaderForFileIgnoresExtVarsForYaml(t *testing.T) {
+	loader, err := loaderForFileWithExtVars("graph.yaml", map[string]string{"env": "prod"}, nil)
+	if err != nil {
+		t.Fatalf("loaderForFileWithExtVars: unexpected error: %v", err)
+	}
+	if _, ok := loader.(*yamlLoader); !ok {
+		t.Fatalf("loaderForFileWithExtVars: got %T, want *yamlLoader", loader)
+	}
+}
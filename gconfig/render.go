@@ -0,0 +1,178 @@
+// Mgmt
+// Copyright (C) 2013-2016+ James Shubin and the project contributors
+// Written by James Shubin <james@shubin.ca> and the project contributors
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package gconfig
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"text/template"
+
+	"github.com/Masterminds/sprig"
+	"gopkg.in/yaml.v3"
+)
+
+// RenderConfigFromFile treats filename as a text/template template,
+// executes it against the values tree assembled from valuesFiles and
+// setOverrides, and then parses the rendered result as a GraphConfig. This
+// is the templated equivalent of ParseConfigFromFile, and lets one config
+// be reused across many hosts by varying the values instead of duplicating
+// vertices and edges per host.
+func RenderConfigFromFile(filename string, valuesFiles []string, setOverrides map[string]interface{}) (*GraphConfig, error) {
+	rendered, err := RenderTemplate(filename, valuesFiles, setOverrides)
+	if err != nil {
+		return nil, err
+	}
+
+	var config GraphConfig
+	if err := config.Parse(rendered); err != nil {
+		return nil, fmt.Errorf("Config: Error: RenderConfigFromFile: Parse: %v", err)
+	}
+
+	return &config, nil
+}
+
+// RenderTemplate renders filename as a text/template template against the
+// merged values, and returns the resulting bytes without parsing them.
+// This backs the `--render-only` flag, so users can inspect or diff the
+// rendered config before it becomes a GraphConfig.
+func RenderTemplate(filename string, valuesFiles []string, setOverrides map[string]interface{}) ([]byte, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("Config: Error: RenderTemplate: File: %v", err)
+	}
+
+	values, err := mergeValues(valuesFiles, setOverrides)
+	if err != nil {
+		return nil, fmt.Errorf("Config: Error: RenderTemplate: Values: %v", err)
+	}
+
+	tmpl, err := template.New(filename).Funcs(templateFuncMap()).Parse(string(data))
+	if err != nil {
+		return nil, fmt.Errorf("Config: Error: RenderTemplate: Parse: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, values); err != nil {
+		return nil, fmt.Errorf("Config: Error: RenderTemplate: Execute: %v", err)
+	}
+
+	return buf.Bytes(), nil
+}
+
+// templateFuncMap returns the text/template functions available inside a
+// rendered config, on top of the standard sprig library.
+func templateFuncMap() template.FuncMap {
+	funcs := sprig.TxtFuncMap()
+	funcs["lookup"] = templateLookup
+	funcs["resource"] = renderResourceStanza
+	funcs["edge"] = renderEdgeStanza
+	return funcs
+}
+
+// templateLookup implements the `lookup` template helper. For now it only
+// knows about the current Hostname, but it's the extension point for
+// looking up other host facts from within a template.
+func templateLookup(key string) (string, error) {
+	if key != "Hostname" {
+		return "", fmt.Errorf("Config: Error: lookup: unknown key: %v", key)
+	}
+	return os.Hostname()
+}
+
+// renderResourceStanza emits a single flow-style flat-list entry for
+// kind/name/fields (eg: `- {kind: file, name: a, content: x}`), so a
+// template loop can emit many resources under `resources:` without
+// hand-indenting YAML. It's flow-style and single-line on purpose: a
+// block-style stanza would need to be re-indented to match wherever the
+// template placed it, and text/template substitution doesn't do that.
+func renderResourceStanza(kind, name string, fields map[string]interface{}) (string, error) {
+	stanza := map[string]interface{}{"kind": kind, "name": name}
+	for k, v := range fields {
+		stanza[k] = v
+	}
+	node := &yaml.Node{}
+	if err := node.Encode(stanza); err != nil {
+		return "", err
+	}
+	node.Style = yaml.FlowStyle
+	out, err := yaml.Marshal(node)
+	if err != nil {
+		return "", err
+	}
+	return "- " + strings.TrimSpace(string(out)) + "\n", nil
+}
+
+// renderEdgeStanza emits a single flow-style flat-list entry for an edge
+// between two kind/name pairs, for use from inside a config template. Like
+// renderResourceStanza, it stays on one line so it lands correctly under
+// `edges:` regardless of where the template places it.
+func renderEdgeStanza(name, fromKind, fromName, toKind, toName string) (string, error) {
+	stanza := edgeConfig{
+		Name: name,
+		From: vertexConfig{Kind: fromKind, Name: fromName},
+		To:   vertexConfig{Kind: toKind, Name: toName},
+	}
+	node := &yaml.Node{}
+	if err := node.Encode(stanza); err != nil {
+		return "", err
+	}
+	node.Style = yaml.FlowStyle
+	out, err := yaml.Marshal(node)
+	if err != nil {
+		return "", err
+	}
+	return "- " + strings.TrimSpace(string(out)) + "\n", nil
+}
+
+// mergeValues builds the values tree available to a config template by
+// reading each values file in order (later files win), and then applying
+// any `--set a.b=c` style overrides on top.
+func mergeValues(valuesFiles []string, setOverrides map[string]interface{}) (map[string]interface{}, error) {
+	values := make(map[string]interface{})
+	for _, f := range valuesFiles {
+		data, err := ioutil.ReadFile(f)
+		if err != nil {
+			return nil, fmt.Errorf("values file: %v", err)
+		}
+		var v map[string]interface{}
+		if err := yaml.Unmarshal(data, &v); err != nil {
+			return nil, fmt.Errorf("values file: %v: %v", f, err)
+		}
+		values = mergeMaps(values, v)
+	}
+	values = mergeMaps(values, setOverrides)
+	return values, nil
+}
+
+// mergeMaps recursively merges src into dst, giving precedence to src, and
+// returns dst.
+func mergeMaps(dst, src map[string]interface{}) map[string]interface{} {
+	for k, v := range src {
+		if dstMap, ok := dst[k].(map[string]interface{}); ok {
+			if srcMap, ok := v.(map[string]interface{}); ok {
+				dst[k] = mergeMaps(dstMap, srcMap)
+				continue
+			}
+		}
+		dst[k] = v
+	}
+	return dst
+}
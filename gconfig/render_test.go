@@ -0,0 +1,86 @@
+// Mgmt
+// Copyright (C) 2013-2016+ James Shubin and the project contributors
+// Written by James Shubin <james@shubin.ca> and the project contributors
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package gconfig
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+// TestRenderResourceStanzaTwice checks that calling the `resource`
+// template helper more than once for the same kind still renders to a
+// GraphConfig with both resources present, instead of landing outside
+// `resources:` or colliding on a duplicate map key.
+func TestRenderResourceStanzaTwice(t *testing.T) {
+	tmpl := "graph: g1\nresources:\n" +
+		`{{ resource "file" "a" (dict "content" "x") }}` + "\n" +
+		`{{ resource "file" "b" (dict "content" "y") }}` + "\n"
+
+	f, err := ioutil.TempFile("", "render-test-*.tmpl")
+	if err != nil {
+		t.Fatalf("TempFile: unexpected error: %v", err)
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.WriteString(tmpl); err != nil {
+		t.Fatalf("WriteString: unexpected error: %v", err)
+	}
+	f.Close()
+
+	config, err := RenderConfigFromFile(f.Name(), nil, nil)
+	if err != nil {
+		t.Fatalf("RenderConfigFromFile: unexpected error: %v", err)
+	}
+	if len(config.Resources) != 2 {
+		t.Fatalf("RenderConfigFromFile: got %v resources, want 2", len(config.Resources))
+	}
+	if config.Resources[0].Name != "a" || config.Resources[1].Name != "b" {
+		t.Errorf("RenderConfigFromFile: got names %q, %q, want \"a\", \"b\"", config.Resources[0].Name, config.Resources[1].Name)
+	}
+}
+
+// TestRenderEdgeStanza checks that the `edge` template helper renders to a
+// GraphConfig with the edge present under `edges:`.
+func TestRenderEdgeStanza(t *testing.T) {
+	tmpl := "graph: g1\nresources:\n" +
+		`{{ resource "file" "a" (dict "content" "x") }}` + "\n" +
+		`{{ resource "file" "b" (dict "content" "y") }}` + "\n" +
+		"edges:\n" +
+		`{{ edge "e1" "file" "a" "file" "b" }}` + "\n"
+
+	f, err := ioutil.TempFile("", "render-test-*.tmpl")
+	if err != nil {
+		t.Fatalf("TempFile: unexpected error: %v", err)
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.WriteString(tmpl); err != nil {
+		t.Fatalf("WriteString: unexpected error: %v", err)
+	}
+	f.Close()
+
+	config, err := RenderConfigFromFile(f.Name(), nil, nil)
+	if err != nil {
+		t.Fatalf("RenderConfigFromFile: unexpected error: %v", err)
+	}
+	if len(config.Edges) != 1 {
+		t.Fatalf("RenderConfigFromFile: got %v edges, want 1", len(config.Edges))
+	}
+	if config.Edges[0].Name != "e1" {
+		t.Errorf("RenderConfigFromFile: got edge name %q, want \"e1\"", config.Edges[0].Name)
+	}
+}
@@ -0,0 +1,166 @@
+// Mgmt
+// Copyright (C) 2013-2016+ James Shubin and the project contributors
+// Written by James Shubin <james@shubin.ca> and the project contributors
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package gconfig
+
+import (
+	"fmt"
+	"log"
+	"reflect"
+
+	"github.com/purpleidea/mgmt/resources"
+
+	"gopkg.in/yaml.v3"
+)
+
+// NOTE: this package moved from gopkg.in/yaml.v2 to gopkg.in/yaml.v3 to get
+// yaml.Node, which per-kind dispatch through the registry needs (decode
+// into the right Go type only once `kind` is known, instead of a fixed
+// struct field per kind). v3 resolves scalars against the YAML 1.2 core
+// schema instead of v2's YAML 1.1, which in general turns `yes`/`no`/
+// `on`/`off` into plain strings rather than bool - but v3 special-cases
+// that back in when the decode target is a statically-typed bool field
+// (see TestLegacyBoolCompat), which is the only way this package ever
+// decodes a resource. The only paths that would actually see the
+// difference are ones that decode into an untyped interface{} or
+// map[string]interface{} before a Res ever gets built; in this package
+// that is mergeValues (render.go), which decodes values files into a
+// map[string]interface{}, so a `yes`/`no` there reaches a config template
+// as a string, not a bool.
+
+// resourceConfig is a single entry in the flat `resources:` list, eg:
+// `{kind: file, name: foo, content: ...}`. Kind picks which factory in the
+// resources.Registry builds the value; the rest of the mapping is decoded
+// directly into whatever that factory returns.
+type resourceConfig struct {
+	Kind string
+	Name string
+
+	node *yaml.Node    // the full mapping, kept around so Build can decode it
+	res  resources.Res // already-built value, set by the legacy shim below
+}
+
+// UnmarshalYAML captures the full yaml.Node for this resource entry so it
+// can be decoded into the right Go type once we know its `kind`.
+func (r *resourceConfig) UnmarshalYAML(value *yaml.Node) error {
+	var meta struct {
+		Kind string `yaml:"kind"`
+		Name string `yaml:"name"`
+	}
+	if err := value.Decode(&meta); err != nil {
+		return err
+	}
+	r.Kind = meta.Kind
+	r.Name = meta.Name
+	r.node = value
+	return nil
+}
+
+// MarshalYAML writes the entry back out as the full mapping it was read
+// from, so a marshalled GraphConfig keeps every field of each resource.
+// Entries that came in via the legacy nested schema have no such mapping
+// and are written as their kind and name only.
+func (r resourceConfig) MarshalYAML() (interface{}, error) {
+	if r.node != nil {
+		return r.node, nil
+	}
+	return struct {
+		Kind string `yaml:"kind"`
+		Name string `yaml:"name"`
+	}{Kind: r.Kind, Name: r.Name}, nil
+}
+
+// Build returns the concrete resources.Res for this entry: either an
+// already-built value (for entries that came in via the legacy nested
+// schema), or a fresh value from the resources.Registry with the rest of
+// the yaml node decoded into it.
+func (r *resourceConfig) Build() (resources.Res, error) {
+	if r.res != nil { // came from the legacy nested schema
+		return r.res, nil
+	}
+
+	res, err := resources.NewResource(r.Kind)
+	if err != nil {
+		return nil, fmt.Errorf("Config: Error: resourceConfig: Build: %v", err)
+	}
+	if err := r.node.Decode(res); err != nil {
+		return nil, fmt.Errorf("Config: Error: resourceConfig: Build: Decode: %v", err)
+	}
+	return res, nil
+}
+
+// resourcesConfig is the `resources:` value in a GraphConfig. It accepts
+// the new flat list schema (`resources: [ {kind: file, name: ...}, ... ]`),
+// and as a compatibility shim for one release, also still accepts the old
+// schema where each kind was its own named field (`resources: {file: [...]}`).
+type resourcesConfig []resourceConfig
+
+// UnmarshalYAML tries the new flat list first; if the node isn't a
+// sequence, it falls back to the old, nested-by-kind mapping so that
+// existing configs keep working for one more release.
+func (r *resourcesConfig) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.SequenceNode {
+		var list []resourceConfig
+		if err := value.Decode(&list); err != nil {
+			return err
+		}
+		*r = list
+		return nil
+	}
+
+	log.Printf("Config: Warning: nested `resources:` schema is deprecated, please switch to the flat list schema")
+	var legacy legacyResourcesConfig
+	if err := value.Decode(&legacy); err != nil {
+		return err
+	}
+	*r = legacy.flatten()
+	return nil
+}
+
+// legacyResourcesConfig is the pre-registry `resources:` schema, where
+// every known kind was a hard-coded, named field.
+type legacyResourcesConfig struct {
+	Noop  []*resources.NoopRes  `yaml:"noop"`
+	Pkg   []*resources.PkgRes   `yaml:"pkg"`
+	File  []*resources.FileRes  `yaml:"file"`
+	Svc   []*resources.SvcRes   `yaml:"svc"`
+	Exec  []*resources.ExecRes  `yaml:"exec"`
+	Timer []*resources.TimerRes `yaml:"timer"`
+	Msg   []*resources.MsgRes   `yaml:"msg"`
+}
+
+// flatten converts the legacy, nested-by-kind resources into the new flat
+// list, carrying over the already-unmarshalled values directly rather than
+// going through the registry.
+func (l *legacyResourcesConfig) flatten() []resourceConfig {
+	var out []resourceConfig
+	add := func(kind string, list interface{}) {
+		value := reflect.ValueOf(list)
+		for i := 0; i < value.Len(); i++ {
+			res := value.Index(i).Interface().(resources.Res)
+			out = append(out, resourceConfig{Kind: kind, Name: res.GetName(), res: res})
+		}
+	}
+	add("noop", l.Noop)
+	add("pkg", l.Pkg)
+	add("file", l.File)
+	add("svc", l.Svc)
+	add("exec", l.Exec)
+	add("timer", l.Timer)
+	add("msg", l.Msg)
+	return out
+}
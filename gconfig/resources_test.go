@@ -0,0 +1,47 @@
+// Mgmt
+// Copyright (C) 2013-2016+ James Shubin and the project contributors
+// Written by James Shubin <james@shubin.ca> and the project contributors
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package gconfig
+
+import "testing"
+
+// TestResourceConfigBuildFromJSON checks that a flat resources: list
+// parsed from JSON text (as produced by the jsonnet loader, since JSON is
+// valid YAML) still populates resourceConfig.node and builds cleanly,
+// instead of leaving it nil and panicking in Build.
+func TestResourceConfigBuildFromJSON(t *testing.T) {
+	data := []byte(`{"graph": "g1", "resources": [{"kind": "noop", "name": "n1"}]}`)
+
+	var config GraphConfig
+	if err := config.Parse(data); err != nil {
+		t.Fatalf("Parse: unexpected error: %v", err)
+	}
+	if len(config.Resources) != 1 {
+		t.Fatalf("Parse: got %v resources, want 1", len(config.Resources))
+	}
+
+	res, err := config.Resources[0].Build()
+	if err != nil {
+		t.Fatalf("Build: unexpected error: %v", err)
+	}
+	if res == nil {
+		t.Fatal("Build: got a nil Res")
+	}
+	if res.GetName() != "n1" {
+		t.Errorf("Build: got name %q, want %q", res.GetName(), "n1")
+	}
+}
@@ -0,0 +1,52 @@
+// Mgmt
+// Copyright (C) 2013-2016+ James Shubin and the project contributors
+// Written by James Shubin <james@shubin.ca> and the project contributors
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package gconfig
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/purpleidea/mgmt/etcd"
+)
+
+// EtcdSecretResolver resolves `secret://etcd/some/key` references by
+// reading them out of the same etcd cluster mgmt already uses for exported
+// resources.
+type EtcdSecretResolver struct {
+	EmbdEtcd *etcd.EmbdEtcd
+}
+
+// NewEtcdSecretResolver builds an EtcdSecretResolver backed by embdEtcd.
+func NewEtcdSecretResolver(embdEtcd *etcd.EmbdEtcd) *EtcdSecretResolver {
+	return &EtcdSecretResolver{EmbdEtcd: embdEtcd}
+}
+
+// Resolve strips the `secret://etcd/` prefix off ref and looks the
+// resulting key up in etcd.
+func (obj *EtcdSecretResolver) Resolve(ref string) ([]byte, error) {
+	key := strings.TrimPrefix(ref, secretPrefix+"etcd/")
+	if key == ref { // prefix didn't match
+		return nil, fmt.Errorf("Config: Error: EtcdSecretResolver: malformed ref: %v", ref)
+	}
+
+	value, err := obj.EmbdEtcd.Get(key)
+	if err != nil {
+		return nil, fmt.Errorf("Config: Error: EtcdSecretResolver: %v", err)
+	}
+	return []byte(value), nil
+}
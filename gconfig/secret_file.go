@@ -0,0 +1,49 @@
+// Mgmt
+// Copyright (C) 2013-2016+ James Shubin and the project contributors
+// Written by James Shubin <james@shubin.ca> and the project contributors
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package gconfig
+
+import (
+	"fmt"
+	"io/ioutil"
+	"strings"
+)
+
+// FileSecretResolver resolves `secret://file:/path/to/secret` references by
+// reading the referenced path straight off the local filesystem.
+type FileSecretResolver struct{}
+
+// init registers the file resolver under the "file" scheme, since it has
+// no configuration of its own and is always safe to have available.
+func init() {
+	RegisterSecretResolver("file", &FileSecretResolver{})
+}
+
+// Resolve strips the `secret://file:` prefix off ref and reads the
+// resulting path.
+func (obj *FileSecretResolver) Resolve(ref string) ([]byte, error) {
+	path := strings.TrimPrefix(ref, secretPrefix+"file:")
+	if path == ref { // prefix didn't match
+		return nil, fmt.Errorf("Config: Error: FileSecretResolver: malformed ref: %v", ref)
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("Config: Error: FileSecretResolver: %v", err)
+	}
+	return data, nil
+}
@@ -0,0 +1,106 @@
+// Mgmt
+// Copyright (C) 2013-2016+ James Shubin and the project contributors
+// Written by James Shubin <james@shubin.ca> and the project contributors
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package gconfig
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	vaultapi "github.com/hashicorp/vault/api"
+)
+
+// VaultSecretResolver resolves `secret://vault/path/to/kv#field` references
+// against a Hashicorp Vault server.
+type VaultSecretResolver struct {
+	client *vaultapi.Client
+}
+
+// init registers the vault resolver under the "vault" scheme when a Vault
+// server address is configured in the environment, the same way the
+// `vault` CLI and other api.Client consumers are normally pointed at a
+// server. Unlike the file resolver, vault has no sensible config-free
+// default, so it only registers itself when VAULT_ADDR is actually set.
+func init() {
+	registerVaultResolverFromEnv()
+}
+
+// registerVaultResolverFromEnv does the env-var lookup and registration
+// init() defers to; split out so a test can call it directly after
+// setting VAULT_ADDR, since init() itself only runs once per process.
+func registerVaultResolverFromEnv() {
+	addr := os.Getenv("VAULT_ADDR")
+	if addr == "" {
+		return // no vault server configured; leave the "vault" scheme unregistered
+	}
+	resolver, err := NewVaultSecretResolver(addr, os.Getenv("VAULT_TOKEN"))
+	if err != nil {
+		log.Printf("Config: Error: VaultSecretResolver: %v", err)
+		return
+	}
+	RegisterSecretResolver("vault", resolver)
+}
+
+// NewVaultSecretResolver builds a VaultSecretResolver talking to addr,
+// authenticated with token.
+func NewVaultSecretResolver(addr, token string) (*VaultSecretResolver, error) {
+	config := vaultapi.DefaultConfig()
+	config.Address = addr
+	client, err := vaultapi.NewClient(config)
+	if err != nil {
+		return nil, fmt.Errorf("Config: Error: VaultSecretResolver: %v", err)
+	}
+	client.SetToken(token)
+
+	return &VaultSecretResolver{client: client}, nil
+}
+
+// Resolve splits ref into a vault path and an optional `#field` (default:
+// "value"), and reads that field out of the secret stored at that path
+// (from its nested "data" map for a KV v2 secret).
+func (obj *VaultSecretResolver) Resolve(ref string) ([]byte, error) {
+	path := strings.TrimPrefix(ref, secretPrefix+"vault/")
+	if path == ref { // prefix didn't match
+		return nil, fmt.Errorf("Config: Error: VaultSecretResolver: malformed ref: %v", ref)
+	}
+
+	field := "value"
+	if i := strings.LastIndex(path, "#"); i >= 0 {
+		field = path[i+1:]
+		path = path[:i]
+	}
+
+	secret, err := obj.client.Logical().Read(path)
+	if err != nil {
+		return nil, fmt.Errorf("Config: Error: VaultSecretResolver: %v", err)
+	}
+	if secret == nil {
+		return nil, fmt.Errorf("Config: Error: VaultSecretResolver: no secret at: %v", path)
+	}
+	data := secret.Data
+	if inner, ok := data["data"].(map[string]interface{}); ok {
+		data = inner // a KV v2 (`kv/data/...`) read nests the fields under "data"
+	}
+	value, ok := data[field].(string)
+	if !ok {
+		return nil, fmt.Errorf("Config: Error: VaultSecretResolver: field %v missing at: %v", field, path)
+	}
+
+	return []byte(value), nil
+}
@@ -0,0 +1,171 @@
+// Mgmt
+// Copyright (C) 2013-2016+ James Shubin and the project contributors
+// Written by James Shubin <james@shubin.ca> and the project contributors
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package gconfig
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/purpleidea/mgmt/resources"
+)
+
+// secretPrefix is the URI scheme that marks a resource field as a secret
+// reference rather than a literal value, eg:
+// `secret://vault/kv/data/db#password` or `secret://file:/etc/mgmt/foo`.
+const secretPrefix = "secret://"
+
+// SecretResolver resolves a secret reference URI into its plaintext value.
+// Resolvers are registered by URI scheme, so a resource field like
+// ExecRes.Cmd or FileRes.Content can reference a secret instead of
+// embedding it in cleartext in the config.
+type SecretResolver interface {
+	// Resolve looks up ref and returns its plaintext value.
+	Resolve(ref string) ([]byte, error)
+}
+
+// secretResolvers maps a secret:// URI scheme (the part right after
+// `secret://`, up to the first `/` or `:`) to the SecretResolver that
+// handles it.
+var secretResolvers = make(map[string]SecretResolver)
+
+// RegisterSecretResolver adds resolver under scheme, so that a reference
+// like `secret://scheme/...` is dispatched to it.
+func RegisterSecretResolver(scheme string, resolver SecretResolver) {
+	secretResolvers[scheme] = resolver
+}
+
+// secretScheme returns the resolver scheme for a secret reference, eg:
+// "vault" for "secret://vault/kv/data/db#password".
+func secretScheme(ref string) string {
+	rest := strings.TrimPrefix(ref, secretPrefix)
+	if i := strings.IndexAny(rest, "/:"); i >= 0 {
+		return rest[:i]
+	}
+	return rest
+}
+
+// resolveResourceSecrets walks res by reflection and replaces any string
+// field that looks like a `secret://` reference with its resolved value.
+// It's meant to be called after yaml unmarshal but before res.Init(), and
+// cache should live for the duration of a single graph-load so the same
+// reference isn't resolved more than once.
+func resolveResourceSecrets(res resources.Res, cache map[string][]byte) error {
+	value := reflect.ValueOf(res)
+	if value.Kind() != reflect.Ptr || value.Elem().Kind() != reflect.Struct {
+		return nil
+	}
+	return resolveSecretsInValue(value.Elem(), cache)
+}
+
+// resolveSecretsInValue recurses through value's fields, resolving any
+// `secret://` string it finds in place.
+func resolveSecretsInValue(value reflect.Value, cache map[string][]byte) error {
+	for i := 0; i < value.NumField(); i++ {
+		field := value.Field(i)
+		if !field.CanSet() {
+			continue
+		}
+		switch field.Kind() {
+		case reflect.String:
+			ref := field.String()
+			if !strings.HasPrefix(ref, secretPrefix) {
+				continue
+			}
+			secret, err := resolveSecret(ref, cache)
+			if err != nil {
+				return err
+			}
+			field.SetString(string(secret))
+		case reflect.Struct:
+			if err := resolveSecretsInValue(field, cache); err != nil {
+				return err
+			}
+		case reflect.Ptr:
+			// a nil pointer means the field wasn't set at all, eg:
+			// FileRes.Content being *string to distinguish "unset"
+			// from "set to empty"; there's nothing to resolve there.
+			if field.IsNil() {
+				continue
+			}
+			if err := resolveSecretsInPtr(field, cache); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// resolveSecretsInPtr resolves a `secret://` reference behind a non-nil
+// pointer field, eg: FileRes.Content when it's a *string. It's split out
+// of resolveSecretsInValue's reflect.Ptr case since the pointed-to value
+// can't be switched on the same way a field can (SetString needs the
+// addressable Elem(), not the Ptr itself).
+func resolveSecretsInPtr(field reflect.Value, cache map[string][]byte) error {
+	elem := field.Elem()
+	switch elem.Kind() {
+	case reflect.String:
+		ref := elem.String()
+		if !strings.HasPrefix(ref, secretPrefix) {
+			return nil
+		}
+		secret, err := resolveSecret(ref, cache)
+		if err != nil {
+			return err
+		}
+		elem.SetString(string(secret))
+	case reflect.Struct:
+		return resolveSecretsInValue(elem, cache)
+	}
+	return nil
+}
+
+// resolveSecret resolves ref using the resolver registered for its scheme,
+// consulting cache first so repeated references within a graph-load only
+// hit the backend once. The resolved value is deliberately never logged.
+func resolveSecret(ref string, cache map[string][]byte) ([]byte, error) {
+	if secret, exists := cache[ref]; exists {
+		return secret, nil
+	}
+	scheme := secretScheme(ref)
+	resolver, exists := secretResolvers[scheme]
+	if !exists {
+		return nil, fmt.Errorf("Config: Error: no SecretResolver registered for scheme: %v", scheme)
+	}
+	secret, err := resolver.Resolve(ref)
+	if err != nil {
+		// NOTE: don't include ref's resolved value in the error, only the reference itself.
+		return nil, fmt.Errorf("Config: Error: could not resolve secret: %v: %v", ref, err)
+	}
+	cache[ref] = secret
+	return secret, nil
+}
+
+// redactSecrets returns s with every secret value resolved so far in cache
+// replaced by a placeholder. Any debug logging that might include a
+// resolved resource (eg: a `%+v` of one) must go through this first, so
+// that turning on verbose logging can't leak a secret into the logs.
+func redactSecrets(s string, cache map[string][]byte) string {
+	for _, secret := range cache {
+		if len(secret) == 0 {
+			continue
+		}
+		s = strings.Replace(s, string(secret), "<redacted>", -1)
+	}
+	return s
+}
@@ -0,0 +1,144 @@
+// Mgmt
+// Copyright (C) 2013-2016+ James Shubin and the project contributors
+// Written by James Shubin <james@shubin.ca> and the project contributors
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package gconfig
+
+import (
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// fakeSecretResolver is a stand-in SecretResolver for tests that don't want
+// to touch the filesystem, etcd, or vault.
+type fakeSecretResolver struct {
+	value []byte
+	err   error
+	calls int
+}
+
+func (obj *fakeSecretResolver) Resolve(ref string) ([]byte, error) {
+	obj.calls++
+	return obj.value, obj.err
+}
+
+// TestFileResolverRegisteredByDefault checks that the "file" scheme is
+// reachable without any explicit wiring, since FileSecretResolver has no
+// configuration and registers itself from init().
+func TestFileResolverRegisteredByDefault(t *testing.T) {
+	if _, exists := secretResolvers["file"]; !exists {
+		t.Error("expected \"file\" SecretResolver to be registered by default")
+	}
+}
+
+// TestResolveSecretCaches checks that resolving the same ref twice only
+// calls the underlying resolver once.
+func TestResolveSecretCaches(t *testing.T) {
+	const scheme = "fake-cache-test"
+	resolver := &fakeSecretResolver{value: []byte("hunter2")}
+	RegisterSecretResolver(scheme, resolver)
+
+	cache := make(map[string][]byte)
+	ref := secretPrefix + scheme + "/db/password"
+
+	for i := 0; i < 2; i++ {
+		secret, err := resolveSecret(ref, cache)
+		if err != nil {
+			t.Fatalf("resolveSecret: unexpected error: %v", err)
+		}
+		if string(secret) != "hunter2" {
+			t.Fatalf("resolveSecret: got %q, want %q", secret, "hunter2")
+		}
+	}
+
+	if resolver.calls != 1 {
+		t.Errorf("expected resolver to be called once, got %v calls", resolver.calls)
+	}
+}
+
+// TestResolveSecretUnknownScheme checks that an unregistered scheme is a
+// clean error.
+func TestResolveSecretUnknownScheme(t *testing.T) {
+	cache := make(map[string][]byte)
+	if _, err := resolveSecret(secretPrefix+"nope/somewhere", cache); err == nil {
+		t.Error("resolveSecret: expected an error for an unregistered scheme")
+	}
+}
+
+// TestRedactSecrets checks that a resolved secret value never survives
+// into a string meant for logging.
+func TestRedactSecrets(t *testing.T) {
+	cache := map[string][]byte{
+		"secret://fake/db": []byte("hunter2"),
+	}
+	in := "&ExecRes{Cmd:hunter2, Name:foo}"
+	out := redactSecrets(in, cache)
+
+	if strings.Contains(out, "hunter2") {
+		t.Errorf("redactSecrets: secret value leaked into: %v", out)
+	}
+	if !strings.Contains(out, "<redacted>") {
+		t.Errorf("redactSecrets: expected a <redacted> placeholder, got: %v", out)
+	}
+}
+
+// TestRegisterVaultResolverFromEnv checks that the "vault" scheme becomes
+// reachable once VAULT_ADDR is set, the same way the file and etcd
+// resolvers are reachable without any explicit wiring by the caller.
+func TestRegisterVaultResolverFromEnv(t *testing.T) {
+	delete(secretResolvers, "vault") // in case an earlier test env had VAULT_ADDR set
+
+	old := os.Getenv("VAULT_ADDR")
+	defer os.Setenv("VAULT_ADDR", old)
+	os.Setenv("VAULT_ADDR", "http://127.0.0.1:8200")
+
+	registerVaultResolverFromEnv()
+
+	if _, exists := secretResolvers["vault"]; !exists {
+		t.Error("expected \"vault\" SecretResolver to be registered once VAULT_ADDR is set")
+	}
+}
+
+// TestResolveSecretsInValuePointerField checks that a `secret://` ref
+// behind a non-nil pointer field (eg: FileRes.Content modeled as *string)
+// is resolved, and that a nil pointer field is left alone.
+func TestResolveSecretsInValuePointerField(t *testing.T) {
+	const scheme = "fake-ptr-test"
+	resolver := &fakeSecretResolver{value: []byte("hunter2")}
+	RegisterSecretResolver(scheme, resolver)
+
+	type fakeFileRes struct {
+		Content *string
+		Unset   *string
+	}
+
+	content := secretPrefix + scheme + "/db/password"
+	res := &fakeFileRes{Content: &content}
+
+	cache := make(map[string][]byte)
+	if err := resolveSecretsInValue(reflect.ValueOf(res).Elem(), cache); err != nil {
+		t.Fatalf("resolveSecretsInValue: unexpected error: %v", err)
+	}
+
+	if *res.Content != "hunter2" {
+		t.Errorf("resolveSecretsInValue: got Content %q, want %q", *res.Content, "hunter2")
+	}
+	if res.Unset != nil {
+		t.Error("resolveSecretsInValue: expected a nil pointer field to stay nil")
+	}
+}
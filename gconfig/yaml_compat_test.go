@@ -0,0 +1,80 @@
+// Mgmt
+// Copyright (C) 2013-2016+ James Shubin and the project contributors
+// Written by James Shubin <james@shubin.ca> and the project contributors
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package gconfig
+
+import (
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+// fakeBoolRes stands in for a resource struct with a YAML-1.1-style
+// boolean field (eg: FileRes.Recurse), so this test doesn't depend on the
+// real resources.Res types.
+type fakeBoolRes struct {
+	Recurse bool `yaml:"recurse"`
+}
+
+// TestLegacyBoolCompat pins that switching this package from yaml.v2 to
+// yaml.v3 (for yaml.Node, see the NOTE on resourceConfig) didn't silently
+// break YAML-1.1-style `yes`/`no`/`on`/`off` booleans in existing configs:
+// v3 still accepts them when decoding directly into a typed bool field,
+// which is the only way this package ever decodes a resource.
+func TestLegacyBoolCompat(t *testing.T) {
+	cases := []struct {
+		in   string
+		want bool
+	}{
+		{"recurse: yes\n", true},
+		{"recurse: no\n", false},
+		{"recurse: on\n", true},
+		{"recurse: off\n", false},
+		{"recurse: true\n", true},
+		{"recurse: false\n", false},
+	}
+	for _, c := range cases {
+		var res fakeBoolRes
+		if err := yaml.Unmarshal([]byte(c.in), &res); err != nil {
+			t.Errorf("Unmarshal(%q): unexpected error: %v", c.in, err)
+			continue
+		}
+		if res.Recurse != c.want {
+			t.Errorf("Unmarshal(%q): got Recurse=%v, want %v", c.in, res.Recurse, c.want)
+		}
+	}
+}
+
+// TestLegacyBoolCompatThroughResourceConfig pins the same guarantee
+// through the actual decode path a flat-list `resources:` entry goes
+// through: resourceConfig captures the raw yaml.Node in UnmarshalYAML,
+// then Decode()s it straight into the target struct, same as Build does
+// once the kind is resolved via the registry.
+func TestLegacyBoolCompatThroughResourceConfig(t *testing.T) {
+	var rc resourceConfig
+	if err := yaml.Unmarshal([]byte("kind: fake\nname: a\nrecurse: yes\n"), &rc); err != nil {
+		t.Fatalf("Unmarshal: unexpected error: %v", err)
+	}
+
+	var res fakeBoolRes
+	if err := rc.node.Decode(&res); err != nil {
+		t.Fatalf("node.Decode: unexpected error: %v", err)
+	}
+	if !res.Recurse {
+		t.Error("node.Decode: got Recurse=false, want true (legacy `yes` should still resolve to bool true)")
+	}
+}
@@ -0,0 +1,42 @@
+// Mgmt
+// Copyright (C) 2013-2016+ James Shubin and the project contributors
+// Written by James Shubin <james@shubin.ca> and the project contributors
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package gconfig
+
+import (
+	"fmt"
+	"io/ioutil"
+)
+
+// yamlLoader implements ConfigLoader for plain yaml graph configs. This is
+// the original, default format.
+type yamlLoader struct{}
+
+// Load reads filename and parses it as yaml into a GraphConfig.
+func (obj *yamlLoader) Load(filename string) (*GraphConfig, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("Config: Error: yamlLoader: File: %v", err)
+	}
+
+	var config GraphConfig
+	if err := config.Parse(data); err != nil {
+		return nil, fmt.Errorf("Config: Error: yamlLoader: Parse: %v", err)
+	}
+
+	return &config, nil
+}
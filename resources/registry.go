@@ -0,0 +1,48 @@
+// Mgmt
+// Copyright (C) 2013-2016+ James Shubin and the project contributors
+// Written by James Shubin <james@shubin.ca> and the project contributors
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package resources
+
+import "fmt"
+
+// registry maps a resource `kind` string (eg: "file", "pkg") to the
+// factory that builds a fresh, empty value of that kind. Resource packages
+// populate this from their own init() via Register, so that gconfig (or
+// any other loader) can build a Res by name without hard-coding every kind
+// that exists.
+var registry = make(map[string]func() Res)
+
+// Register adds kind to the resource registry. It's meant to be called
+// from the init() of the package that implements that resource kind, and
+// panics on a duplicate registration since that can only be a programming
+// error.
+func Register(kind string, factory func() Res) {
+	if _, exists := registry[kind]; exists {
+		panic(fmt.Sprintf("resources: Register: kind already registered: %v", kind))
+	}
+	registry[kind] = factory
+}
+
+// NewResource looks up kind in the registry and returns a fresh Res of
+// that kind, or an error if no such kind has been registered.
+func NewResource(kind string) (Res, error) {
+	factory, exists := registry[kind]
+	if !exists {
+		return nil, fmt.Errorf("resources: NewResource: unknown kind: %v", kind)
+	}
+	return factory(), nil
+}
@@ -0,0 +1,56 @@
+// Mgmt
+// Copyright (C) 2013-2016+ James Shubin and the project contributors
+// Written by James Shubin <james@shubin.ca> and the project contributors
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package resources
+
+import "testing"
+
+// TestRegistryBuiltinKinds makes sure every kind registered in builtin.go
+// is actually reachable through the registry, which is the whole point of
+// this package.
+func TestRegistryBuiltinKinds(t *testing.T) {
+	kinds := []string{"noop", "pkg", "file", "svc", "exec", "timer", "msg"}
+	for _, kind := range kinds {
+		res, err := NewResource(kind)
+		if err != nil {
+			t.Errorf("NewResource(%q): unexpected error: %v", kind, err)
+			continue
+		}
+		if res == nil {
+			t.Errorf("NewResource(%q): got a nil Res", kind)
+		}
+	}
+}
+
+// TestRegistryUnknownKind checks that an unregistered kind is a clean
+// error instead of a nil Res that panics downstream.
+func TestRegistryUnknownKind(t *testing.T) {
+	if _, err := NewResource("bogus"); err == nil {
+		t.Error("NewResource(\"bogus\"): expected an error, got nil")
+	}
+}
+
+// TestRegisterDuplicatePanics checks that re-registering an existing kind
+// is treated as a programming error.
+func TestRegisterDuplicatePanics(t *testing.T) {
+	defer func() {
+		if r := recover(); r == nil {
+			t.Error("Register(\"noop\", ...): expected a panic on duplicate kind")
+		}
+	}()
+	Register("noop", func() Res { return &NoopRes{} })
+}